@@ -39,21 +39,41 @@ type NonBlockingGRPCServer interface {
 	ForceStop()
 }
 
-func NewNonBlockingGRPCServer() NonBlockingGRPCServer {
-	return &nonBlockingGRPCServer{}
+// NewNonBlockingGRPCServer creates a server whose request logs have the values
+// of redactedAttributes and all secrets removed.
+func NewNonBlockingGRPCServer(redactedAttributes ...string) NonBlockingGRPCServer {
+	return &nonBlockingGRPCServer{redactedAttributes: redactedAttributes}
 }
 
 // NonBlocking server
 type nonBlockingGRPCServer struct {
-	wg     sync.WaitGroup
-	server *grpc.Server
+	wg                 sync.WaitGroup
+	server             *grpc.Server
+	redactedAttributes []string
 }
 
 func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+	// the server is created before serving starts so that Stop and ForceStop
+	// always see it, even when called before the listener is up
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(logGRPC(s.redactedAttributes)),
+	}
+	server := grpc.NewServer(opts...)
+	s.server = server
+
+	if ids != nil {
+		csi.RegisterIdentityServer(server, ids)
+	}
+	if cs != nil {
+		csi.RegisterControllerServer(server, cs)
+	}
+	if ns != nil {
+		csi.RegisterNodeServer(server, ns)
+	}
 
 	s.wg.Add(1)
 
-	go s.serve(endpoint, ids, cs, ns)
+	go s.serve(endpoint)
 }
 
 func (s *nonBlockingGRPCServer) Wait() {
@@ -61,14 +81,19 @@ func (s *nonBlockingGRPCServer) Wait() {
 }
 
 func (s *nonBlockingGRPCServer) Stop() {
-	s.server.GracefulStop()
+	if s.server != nil {
+		s.server.GracefulStop()
+	}
 }
 
 func (s *nonBlockingGRPCServer) ForceStop() {
-	s.server.Stop()
+	if s.server != nil {
+		s.server.Stop()
+	}
 }
 
-func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+func (s *nonBlockingGRPCServer) serve(endpoint string) {
+	defer s.wg.Done()
 
 	proto, addr, err := ParseEndpoint(endpoint)
 	if err != nil {
@@ -77,6 +102,9 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, c
 
 	if proto == "unix" {
 		addr = "/" + addr
+		if err := ValidateUnixSocketPath(addr); err != nil {
+			glog.Fatal(err.Error())
+		}
 		if err := os.Remove(addr); err != nil && !os.IsNotExist(err) {
 			glog.Fatalf("Failed to remove %s, error: %s", addr, err.Error())
 		}
@@ -87,25 +115,13 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, c
 		glog.Fatalf("Failed to listen: %v", err)
 	}
 
-	opts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(logGRPC),
-	}
-	server := grpc.NewServer(opts...)
-	s.server = server
-
-	if ids != nil {
-		csi.RegisterIdentityServer(server, ids)
-	}
-	if cs != nil {
-		csi.RegisterControllerServer(server, cs)
-	}
-	if ns != nil {
-		csi.RegisterNodeServer(server, ns)
-	}
-
 	glog.Infof("Listening for connections on address: %#v", listener.Addr())
 
-	err = server.Serve(listener)
+	err = s.server.Serve(listener)
+	if err == grpc.ErrServerStopped {
+		glog.Infof("Server stopped before serving on address: %#v", listener.Addr())
+		return
+	}
 	if err != nil {
 		glog.Fatalf("Failed to serve: %v", err)
 	}
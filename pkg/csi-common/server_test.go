@@ -0,0 +1,86 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csicommon
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitReturns reports whether s.Wait returns within timeout
+func waitReturns(s NonBlockingGRPCServer, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestNonBlockingGRPCServerStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "csi-common")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sock := path.Join(dir, "csi.sock")
+
+	d := NewFakeDriver()
+	s := NewNonBlockingGRPCServer()
+	s.Start("unix://"+sock, NewDefaultIdentityServer(d), NewDefaultControllerServer(d), nil)
+
+	// Test Wait returns once a serving server is stopped
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(sock); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Stop()
+	assert.True(t, waitReturns(s, 5*time.Second))
+}
+
+func TestNonBlockingGRPCServerStopBeforeServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "csi-common")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Test a stop issued right after Start is not lost
+	s := NewNonBlockingGRPCServer()
+	s.Start("unix://"+path.Join(dir, "csi.sock"), nil, nil, nil)
+	s.Stop()
+	assert.True(t, waitReturns(s, 5*time.Second))
+}
+
+func TestNonBlockingGRPCServerForceStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "csi-common")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := NewNonBlockingGRPCServer()
+	s.Start("unix://"+path.Join(dir, "csi.sock"), nil, nil, nil)
+	s.ForceStop()
+	assert.True(t, waitReturns(s, 5*time.Second))
+}
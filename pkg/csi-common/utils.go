@@ -18,6 +18,7 @@ package csicommon
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
@@ -36,6 +37,19 @@ func ParseEndpoint(ep string) (string, string, error) {
 	return "", "", fmt.Errorf("Invalid endpoint: %v", ep)
 }
 
+// maxUnixPathLen is the size of sun_path in sockaddr_un on linux, less the
+// terminating NUL
+const maxUnixPathLen = 107
+
+// ValidateUnixSocketPath returns a descriptive error if addr is too long to be
+// bound as a unix socket, which otherwise fails with a cryptic EINVAL.
+func ValidateUnixSocketPath(addr string) error {
+	if len(addr) > maxUnixPathLen {
+		return fmt.Errorf("unix socket path %s is %d bytes long, exceeding the limit of %d, use a shorter endpoint path", addr, len(addr), maxUnixPathLen)
+	}
+	return nil
+}
+
 func NewVolumeCapabilityAccessMode(mode csi.VolumeCapability_AccessMode_Mode) *csi.VolumeCapability_AccessMode {
 	return &csi.VolumeCapability_AccessMode{Mode: mode}
 }
@@ -92,14 +106,73 @@ func RunControllerandNodePublishServer(endpoint string, d *CSIDriver, cs csi.Con
 	s.Wait()
 }
 
-func logGRPC(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	glog.V(3).Infof("GRPC call: %s", info.FullMethod)
-	glog.V(5).Infof("GRPC request: %+v", req)
-	resp, err := handler(ctx, req)
-	if err != nil {
-		glog.Errorf("GRPC error: %v", err)
-	} else {
-		glog.V(5).Infof("GRPC response: %+v", resp)
+// RedactedValue replaces sensitive values in logs
+const RedactedValue = "[REDACTED]"
+
+// RedactAll returns a copy of m with every value replaced by RedactedValue.
+func RedactAll(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(m))
+	for k := range m {
+		redacted[k] = RedactedValue
+	}
+	return redacted
+}
+
+// RedactKeys returns a copy of m with the values of the given keys replaced by
+// RedactedValue. If no keys are given, nothing is redacted.
+func RedactKeys(m map[string]string, keys ...string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(m))
+	for k, v := range m {
+		redacted[k] = v
+	}
+	for _, k := range keys {
+		if _, ok := redacted[k]; ok {
+			redacted[k] = RedactedValue
+		}
+	}
+	return redacted
+}
+
+var stringMapType = reflect.TypeOf(map[string]string{})
+
+// sanitizeRequest returns a copy of req that is safe to log: every value of
+// its Secrets field is redacted, as are the values of redactedAttributes in its
+// VolumeContext. The CSI spec keeps both fields at the top level of requests,
+// so this covers every request carrying secrets without listing their types.
+func sanitizeRequest(req interface{}, redactedAttributes []string) interface{} {
+	v := reflect.ValueOf(req)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return req
+	}
+	sanitized := reflect.New(v.Elem().Type())
+	sanitized.Elem().Set(v.Elem())
+	if f := sanitized.Elem().FieldByName("Secrets"); f.IsValid() && f.Type() == stringMapType {
+		f.Set(reflect.ValueOf(RedactAll(f.Interface().(map[string]string))))
+	}
+	if f := sanitized.Elem().FieldByName("VolumeContext"); f.IsValid() && f.Type() == stringMapType {
+		f.Set(reflect.ValueOf(RedactKeys(f.Interface().(map[string]string), redactedAttributes...)))
+	}
+	return sanitized.Interface()
+}
+
+// logGRPC returns an interceptor logging calls, with the values of
+// redactedAttributes and all secrets removed from logged requests.
+func logGRPC(redactedAttributes []string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		glog.V(3).Infof("GRPC call: %s", info.FullMethod)
+		glog.V(5).Infof("GRPC request: %+v", sanitizeRequest(req, redactedAttributes))
+		resp, err := handler(ctx, req)
+		if err != nil {
+			glog.Errorf("GRPC error: %v", err)
+		} else {
+			glog.V(5).Infof("GRPC response: %+v", resp)
+		}
+		return resp, err
 	}
-	return resp, err
 }
@@ -17,9 +17,18 @@ limitations under the License.
 package csicommon
 
 import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/glog"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 )
 
 func TestParseEndpoint(t *testing.T) {
@@ -74,3 +83,121 @@ func TestParseEndpoint(t *testing.T) {
 	_, _, err = ParseEndpoint("")
 	assert.NotNil(t, err)
 }
+
+func TestValidateUnixSocketPath(t *testing.T) {
+	assert.NoError(t, ValidateUnixSocketPath("/tmp/csi.sock"))
+
+	// Test a path at the limit
+	addr := "/" + strings.Repeat("a", maxUnixPathLen-1)
+	assert.NoError(t, ValidateUnixSocketPath(addr))
+
+	// Test a path over the limit
+	err := ValidateUnixSocketPath(addr + "a")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "shorter endpoint path")
+}
+
+func TestRedactAll(t *testing.T) {
+	m := map[string]string{"clientid": "fakeid", "clientsecret": "fakesecret"}
+
+	r := RedactAll(m)
+	assert.Equal(t, map[string]string{"clientid": RedactedValue, "clientsecret": RedactedValue}, r)
+
+	// Test original map is left untouched
+	assert.Equal(t, "fakesecret", m["clientsecret"])
+
+	assert.Nil(t, RedactAll(nil))
+}
+
+func TestRedactKeys(t *testing.T) {
+	m := map[string]string{"keyvaultName": "fakevault", "tenantId": "faketenant"}
+
+	// Test nothing is redacted when no keys are given
+	assert.Equal(t, m, RedactKeys(m))
+
+	// Test only the given keys are redacted
+	r := RedactKeys(m, "tenantId", "missing")
+	assert.Equal(t, map[string]string{"keyvaultName": "fakevault", "tenantId": RedactedValue}, r)
+
+	// Test original map is left untouched
+	assert.Equal(t, "faketenant", m["tenantId"])
+
+	assert.Nil(t, RedactKeys(nil, "tenantId"))
+}
+
+func TestSanitizeRequest(t *testing.T) {
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId:      "fakevolume",
+		Secrets:       map[string]string{"clientsecret": "fakesecret"},
+		VolumeContext: map[string]string{"keyvaultName": "fakevault", "tenantId": "faketenant"},
+	}
+
+	logged := fmt.Sprintf("%+v", sanitizeRequest(req, []string{"tenantId"}))
+	assert.NotContains(t, logged, "fakesecret")
+	assert.NotContains(t, logged, "faketenant")
+	assert.Contains(t, logged, "fakevault")
+	assert.Contains(t, logged, "fakevolume")
+
+	// Test the original request is left untouched
+	assert.Equal(t, "fakesecret", req.Secrets["clientsecret"])
+	assert.Equal(t, "faketenant", req.VolumeContext["tenantId"])
+
+	// Test secrets are redacted on controller requests too
+	for _, r := range []interface{}{
+		&csi.CreateVolumeRequest{Name: "fakevolume", Secrets: map[string]string{"clientsecret": "fakesecret"}},
+		&csi.DeleteVolumeRequest{VolumeId: "fakevolume", Secrets: map[string]string{"clientsecret": "fakesecret"}},
+		&csi.ControllerPublishVolumeRequest{VolumeId: "fakevolume", Secrets: map[string]string{"clientsecret": "fakesecret"}},
+		&csi.CreateSnapshotRequest{Name: "fakesnapshot", Secrets: map[string]string{"clientsecret": "fakesecret"}},
+	} {
+		logged = fmt.Sprintf("%+v", sanitizeRequest(r, nil))
+		assert.NotContains(t, logged, "fakesecret")
+		assert.Contains(t, logged, RedactedValue)
+	}
+
+	other := &csi.NodeUnpublishVolumeRequest{VolumeId: "fakevolume"}
+	assert.Equal(t, other, sanitizeRequest(other, nil))
+}
+
+// captureLogs returns what glog writes at verbosity 5 while f runs
+func captureLogs(t *testing.T, f func()) string {
+	for name, value := range map[string]string{"logtostderr": "true", "v": "5"} {
+		old := flag.Lookup(name).Value.String()
+		assert.NoError(t, flag.Set(name, value))
+		defer flag.Set(name, old)
+	}
+	out, err := ioutil.TempFile("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	stderr := os.Stderr
+	os.Stderr = out
+	f()
+	glog.Flush()
+	os.Stderr = stderr
+
+	logs, err := ioutil.ReadFile(out.Name())
+	assert.NoError(t, err)
+	return string(logs)
+}
+
+func TestLogGRPC(t *testing.T) {
+	req := &csi.NodePublishVolumeRequest{
+		VolumeId:      "fakevolume",
+		Secrets:       map[string]string{"clientsecret": "fakesecret"},
+		VolumeContext: map[string]string{"keyvaultName": "fakevault", "tenantId": "faketenant"},
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/csi.v1.Node/NodePublishVolume"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &csi.NodePublishVolumeResponse{}, nil
+	}
+
+	logs := captureLogs(t, func() {
+		_, err := logGRPC([]string{"tenantId"})(context.Background(), req, info, handler)
+		assert.NoError(t, err)
+	})
+	assert.Contains(t, logs, "NodePublishVolume")
+	assert.Contains(t, logs, "fakevault")
+	assert.NotContains(t, logs, "fakesecret")
+	assert.NotContains(t, logs, "faketenant")
+}
@@ -22,7 +22,6 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
-	"path"
 	"regexp"
 	"strings"
 
@@ -35,6 +34,8 @@ import (
 	"github.com/Azure/go-autorest/autorest/adal"
 	"github.com/Azure/go-autorest/autorest/azure"
 
+	csicommon "github.com/deislabs/secrets-store-csi-driver/pkg/csi-common"
+	"github.com/deislabs/secrets-store-csi-driver/pkg/providers"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 )
@@ -92,6 +93,8 @@ type Provider struct {
 	PodName string
 	// the namespace of the pod (if using POD AAD Identity)
 	PodNamespace string
+	// driver settings applied by the provider
+	config providers.Config
 }
 
 type KeyVaultObject struct {
@@ -108,9 +111,9 @@ type StringArray struct {
 }
 
 // NewProvider creates a new Azure Key Vault Provider.
-func NewProvider() (*Provider, error) {
+func NewProvider(config providers.Config) (*Provider, error) {
 	glog.V(2).Infof("NewAzureProvider")
-	var p Provider
+	p := Provider{config: config}
 	return &p, nil
 }
 
@@ -171,20 +174,16 @@ func GetCredential(secrets map[string]string) (string, string, error) {
 	}
 
 	if clientID == "" {
-		return "", "", fmt.Errorf("could not find clientid in secrets(%v)", secrets)
+		return "", "", fmt.Errorf("could not find clientid in secrets")
 	}
 	if clientSecret == "" {
-		return "", "", fmt.Errorf("could not find clientsecret in secrets(%v)", secrets)
+		return "", "", fmt.Errorf("could not find clientsecret in secrets")
 	}
 
 	return clientID, clientSecret, nil
 }
 
 func (p *Provider) getVaultURL(ctx context.Context, cloudName string) (vaultURL *string, err error) {
-	glog.V(5).Infof("subscriptionID: %s", p.SubscriptionID)
-	glog.V(5).Infof("vaultName: %s", p.KeyvaultName)
-	glog.V(5).Infof("resourceGroup: %s", p.ResourceGroup)
-
 	vaultsClient := kvmgmt.NewVaultsClient(p.SubscriptionID)
 	token, tokenErr := p.GetManagementToken(AuthGrantType(), cloudName)
 	if tokenErr != nil {
@@ -309,6 +308,10 @@ func (p *Provider) MountSecretsStoreObjectContent(ctx context.Context, attrib ma
 	if tenantID == "" {
 		return fmt.Errorf("tenantId is not set")
 	}
+	logAttrib := csicommon.RedactKeys(attrib, p.config.RedactedAttributes...)
+	glog.V(5).Infof("subscriptionID: %s", logAttrib["subscriptionId"])
+	glog.V(5).Infof("vaultName: %s", logAttrib["keyvaultName"])
+	glog.V(5).Infof("resourceGroup: %s", logAttrib["resourceGroup"])
 	// defaults
 	usePodIdentity := false
 	if usePodIdentityStr == "true" {
@@ -328,7 +331,7 @@ func (p *Provider) MountSecretsStoreObjectContent(ctx context.Context, attrib ma
 	if objectsStrings == "" {
 		return fmt.Errorf("objects is not set")
 	}
-	glog.V(5).Infof("objects: %s", objectsStrings)
+	glog.V(5).Infof("objects: %s", logAttrib["objects"])
 
 	var objects StringArray
 	err = yaml.Unmarshal([]byte(objectsStrings), &objects)
@@ -336,7 +339,9 @@ func (p *Provider) MountSecretsStoreObjectContent(ctx context.Context, attrib ma
 		glog.V(0).Infof("unmarshal failed for objects")
 		return err
 	}
-	glog.V(5).Infof("objects array: %v", objects.Array)
+	if err := p.config.CheckObjectCount(len(objects.Array)); err != nil {
+		return err
+	}
 	keyVaultObjects := []KeyVaultObject{}
 	for i, object := range objects.Array {
 		var keyVaultObject KeyVaultObject
@@ -348,7 +353,6 @@ func (p *Provider) MountSecretsStoreObjectContent(ctx context.Context, attrib ma
 		keyVaultObjects = append(keyVaultObjects, keyVaultObject)
 	}
 
-	glog.V(5).Infof("unmarshaled keyVaultObjects: %v", keyVaultObjects)
 	glog.V(0).Infof("keyVaultObjects len: %d", len(keyVaultObjects))
 
 	if len(keyVaultObjects) == 0 {
@@ -366,8 +370,8 @@ func (p *Provider) MountSecretsStoreObjectContent(ctx context.Context, attrib ma
 			return err
 		}
 		objectContent := []byte(content)
-		if err := ioutil.WriteFile(path.Join(targetPath, keyVaultObject.ObjectName), objectContent, permission); err != nil {
-			return errors.Wrapf(err, "Secrets Store csi driver failed to mount %s at %s", keyVaultObject.ObjectName, targetPath)
+		if err := providers.WriteFile(targetPath, keyVaultObject.ObjectName, objectContent, permission, p.config.Write); err != nil {
+			return err
 		}
 		glog.V(0).Infof("Secrets Store csi driver mounted %s", keyVaultObject.ObjectName)
 		glog.V(5).Infof("Mount point: %s", targetPath)
@@ -0,0 +1,238 @@
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// Path limits from linux/limits.h
+const (
+	maxPathLen = 4095 // PATH_MAX less the terminating NUL
+	maxNameLen = 255  // NAME_MAX
+)
+
+const (
+	// tmpPrefix prefixes temporary files written next to their target
+	tmpPrefix = ".tmp-"
+	// maxTmpNameLen is the longest temporary file name, ioutil.TempFile
+	// appends a random uint32 of up to 10 digits to the prefix
+	maxTmpNameLen = len(tmpPrefix) + 10
+)
+
+// Policies for a directory found where a file should be written, or a file
+// where a directory should be
+const (
+	// TypeMismatchError fails the write, leaving the entry for an operator
+	TypeMismatchError = "error"
+	// TypeMismatchReplace removes the entry and writes in its place
+	TypeMismatchReplace = "replace"
+)
+
+// rename is os.Rename, overridden in tests
+var rename = os.Rename
+
+var (
+	// ErrTargetReadOnly is returned when the target path is on a read-only filesystem
+	ErrTargetReadOnly = errors.New("target path filesystem is read-only")
+	// ErrNoSpace is returned when the target path filesystem is out of space
+	ErrNoSpace = errors.New("no space left on target path filesystem")
+	// ErrReservedName is returned when writing a file the driver reserves
+	ErrReservedName = errors.New("file name is reserved by the driver")
+)
+
+// WriteOptions configures the checks WriteFile applies before writing. The
+// zero value accepts any content.
+type WriteOptions struct {
+	// MaxFileSize is the largest content in bytes a file may have, unlimited if 0
+	MaxFileSize int64
+	// RejectEmpty fails writes of empty content, which usually means a
+	// provider returned no value rather than an intentionally empty secret
+	RejectEmpty bool
+	// ReservedNames are files under the target path that only the driver
+	// writes, such as the ready file
+	ReservedNames []string
+	// TypeMismatch is the policy for file and directory type mismatches,
+	// TypeMismatchError if empty
+	TypeMismatch string
+}
+
+// WriteFile atomically writes content to the file name under targetPath,
+// replacing any existing file so no bytes of previous content remain. The file
+// gets exactly permission, independent of the process umask. Errors
+// caused by a read-only or full filesystem have ErrTargetReadOnly or
+// ErrNoSpace as their cause so callers can tell node issues apart from other
+// write failures, and writes to a reserved name have ErrReservedName.
+func WriteFile(targetPath string, name string, content []byte, permission os.FileMode, opts WriteOptions) error {
+	file := path.Join(targetPath, name)
+	if err := checkReserved(targetPath, file, opts); err != nil {
+		return wrapWriteError(err, name, targetPath)
+	}
+	if err := checkContent(file, content, opts); err != nil {
+		return wrapWriteError(err, name, targetPath)
+	}
+	if err := checkPathLength(file); err != nil {
+		return wrapWriteError(err, name, targetPath)
+	}
+	if err := checkFileType(targetPath, file, opts.TypeMismatch); err != nil {
+		return wrapWriteError(err, name, targetPath)
+	}
+	if err := writeFileAtomic(file, content, permission); err != nil {
+		return wrapWriteError(err, name, targetPath)
+	}
+	return nil
+}
+
+// checkReserved returns ErrReservedName if file is one of the names opts
+// reserves under targetPath
+func checkReserved(targetPath string, file string, opts WriteOptions) error {
+	for _, name := range opts.ReservedNames {
+		if file == path.Join(targetPath, name) {
+			return ErrReservedName
+		}
+	}
+	return nil
+}
+
+// checkContent returns an error naming file if content is rejected by opts
+func checkContent(file string, content []byte, opts WriteOptions) error {
+	if opts.RejectEmpty && len(content) == 0 {
+		return errors.Errorf("content of %s is empty", file)
+	}
+	if opts.MaxFileSize > 0 && int64(len(content)) > opts.MaxFileSize {
+		return errors.Errorf("content of %s is %d bytes, exceeding the limit of %d", file, len(content), opts.MaxFileSize)
+	}
+	return nil
+}
+
+// checkPathLength returns a descriptive error if file, the temporary file
+// written next to it, or one of its elements exceeds the platform limits,
+// which otherwise surface as a bare ENAMETOOLONG.
+func checkPathLength(file string) error {
+	if len(file) > maxPathLen {
+		return errors.Errorf("path %s is %d bytes long, exceeding the limit of %d", file, len(file), maxPathLen)
+	}
+	dir := path.Dir(file)
+	if tmpLen := len(dir) + 1 + maxTmpNameLen; tmpLen > maxPathLen {
+		return errors.Errorf("temporary file path in %s would be %d bytes long, exceeding the limit of %d", dir, tmpLen, maxPathLen)
+	}
+	for _, elem := range strings.Split(file, "/") {
+		if len(elem) > maxNameLen {
+			return errors.Errorf("path element %s is %d bytes long, exceeding the limit of %d", elem, len(elem), maxNameLen)
+		}
+	}
+	return nil
+}
+
+// checkFileType returns a descriptive error if a directory exists where file
+// should be written, or if its parent exists but is not a directory. Unless
+// policy is TypeMismatchReplace, either is left for an operator to resolve
+// rather than removed.
+func checkFileType(targetPath string, file string, policy string) error {
+	if info, err := os.Lstat(file); err == nil && info.IsDir() {
+		if policy != TypeMismatchReplace {
+			return errors.Errorf("%s is a directory, expected a file", file)
+		}
+		if err := os.RemoveAll(file); err != nil {
+			return err
+		}
+	}
+	dir := path.Dir(file)
+	info, err := os.Stat(dir)
+	if err == nil && !info.IsDir() {
+		if policy != TypeMismatchReplace {
+			return errors.Errorf("%s is a file, expected a directory", dir)
+		}
+		return replaceWithDir(targetPath, dir)
+	}
+	if pe, ok := err.(*os.PathError); ok && pe.Err == syscall.ENOTDIR {
+		if policy != TypeMismatchReplace {
+			return errors.Errorf("an element of %s is a file, expected a directory", dir)
+		}
+		return replaceWithDir(targetPath, dir)
+	}
+	return nil
+}
+
+// replaceWithDir removes the first element of dir below targetPath that is not
+// a directory and creates dir in its place.
+func replaceWithDir(targetPath string, dir string) error {
+	root := path.Clean(targetPath)
+	if !strings.HasPrefix(dir, root+"/") {
+		return errors.Errorf("%s is outside of %s, refusing to replace it", dir, root)
+	}
+	elem := root
+	for _, name := range strings.Split(strings.TrimPrefix(dir, root+"/"), "/") {
+		elem = path.Join(elem, name)
+		info, err := os.Lstat(elem)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			if err := os.Remove(elem); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return os.MkdirAll(dir, 0755)
+}
+
+// writeFileAtomic writes content to a temporary file next to file and renames
+// it into place, so readers only ever observe the old or the new content. The
+// temporary file is created in the same directory because rename(2) is only
+// atomic within a single filesystem.
+func writeFileAtomic(file string, content []byte, permission os.FileMode) (err error) {
+	// a fixed prefix keeps the temporary name within NAME_MAX for long names
+	tmp, err := ioutil.TempFile(path.Dir(file), tmpPrefix)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(content); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(permission); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = rename(tmp.Name(), file); err != nil {
+		if le, ok := err.(*os.LinkError); ok && le.Err == syscall.EXDEV {
+			return errors.Errorf("%s and %s are on different filesystems, rename would not be atomic", tmp.Name(), file)
+		}
+		return err
+	}
+	return nil
+}
+
+func wrapWriteError(err error, name string, targetPath string) error {
+	var errno error
+	switch e := err.(type) {
+	case *os.PathError:
+		errno = e.Err
+	case *os.LinkError:
+		errno = e.Err
+	}
+	switch errno {
+	case syscall.EROFS:
+		err = ErrTargetReadOnly
+	case syscall.ENOSPC:
+		err = ErrNoSpace
+	}
+	return errors.Wrapf(err, "secrets-store csi driver failed to write %s at %s", name, targetPath)
+}
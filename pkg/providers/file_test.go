@@ -0,0 +1,322 @@
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = WriteFile(dir, "secret", []byte("value"), 0644, WriteOptions{})
+	assert.NoError(t, err)
+	content, err := ioutil.ReadFile(path.Join(dir, "secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, "value", string(content))
+
+	// Test missing target path is not reported as a node issue
+	err = WriteFile(path.Join(dir, "missing"), "secret", []byte("value"), 0644, WriteOptions{})
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrTargetReadOnly, errors.Cause(err))
+	assert.NotEqual(t, ErrNoSpace, errors.Cause(err))
+}
+
+func TestWriteFileTruncates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Test replacing a longer file with shorter content leaves no old bytes
+	assert.NoError(t, WriteFile(dir, "secret", []byte("a-much-longer-old-value"), 0644, WriteOptions{}))
+	assert.NoError(t, WriteFile(dir, "secret", []byte("new"), 0644, WriteOptions{}))
+	content, err := ioutil.ReadFile(path.Join(dir, "secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+}
+
+func TestWriteFileEmpty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Test empty content is written by default
+	assert.NoError(t, WriteFile(dir, "empty", []byte{}, 0644, WriteOptions{}))
+	info, err := os.Stat(path.Join(dir, "empty"))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	// Test empty content is rejected by path when configured
+	opts := WriteOptions{RejectEmpty: true}
+	err = WriteFile(dir, "secret", nil, 0644, opts)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), path.Join(dir, "secret")+" is empty")
+	assert.Equal(t, []string{"empty"}, dirNames(t, dir))
+
+	assert.NoError(t, WriteFile(dir, "secret", []byte("value"), 0644, opts))
+}
+
+func TestWriteFileMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Test content at the limit is written
+	opts := WriteOptions{MaxFileSize: 5}
+	assert.NoError(t, WriteFile(dir, "secret", []byte("value"), 0644, opts))
+
+	// Test content above the limit is rejected by path and nothing is written
+	err = WriteFile(dir, "large", []byte("values"), 0644, opts)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), path.Join(dir, "large")+" is 6 bytes, exceeding the limit of 5")
+	assert.Equal(t, []string{"secret"}, dirNames(t, dir))
+
+	// Test the size is unlimited by default
+	assert.NoError(t, WriteFile(dir, "large", []byte("values"), 0644, WriteOptions{}))
+}
+
+func TestWriteFileReservedName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Test reserved names are rejected however the path is spelled
+	opts := WriteOptions{ReservedNames: []string{"..ready"}}
+	for _, name := range []string{"..ready", "./..ready", "/..ready"} {
+		err = WriteFile(dir, name, []byte("value"), 0644, opts)
+		assert.Equal(t, ErrReservedName, errors.Cause(err), name)
+	}
+	assert.Empty(t, dirNames(t, dir))
+
+	// Test the reserved name is only reserved directly under the target path
+	assert.NoError(t, os.Mkdir(path.Join(dir, "dir"), 0755))
+	assert.NoError(t, WriteFile(dir, "dir/..ready", []byte("value"), 0644, opts))
+	assert.NoError(t, WriteFile(dir, "..ready", []byte{}, 0644, WriteOptions{}))
+}
+
+// dirNames returns the sorted names of the entries in dir
+func dirNames(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	return names
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, WriteFile(dir, "secret", []byte("old"), 0644, WriteOptions{}))
+	assert.NoError(t, WriteFile(dir, "secret", []byte("new"), 0644, WriteOptions{}))
+	content, err := ioutil.ReadFile(path.Join(dir, "secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+	assert.Equal(t, []string{"secret"}, dirNames(t, dir))
+
+	// Test a rename onto a non-empty directory fails, leaves the target
+	// unchanged and removes the temporary file
+	target := path.Join(dir, "dir")
+	assert.NoError(t, os.Mkdir(target, 0755))
+	assert.NoError(t, ioutil.WriteFile(path.Join(target, "file"), []byte("kept"), 0644))
+	err = writeFileAtomic(target, []byte("new"), 0644)
+	assert.Error(t, err)
+	info, err := os.Stat(target)
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+	content, err = ioutil.ReadFile(path.Join(target, "file"))
+	assert.NoError(t, err)
+	assert.Equal(t, "kept", string(content))
+	assert.Equal(t, []string{"dir", "secret"}, dirNames(t, dir))
+}
+
+func TestWriteFileAtomicCrossDevice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer func() { rename = os.Rename }()
+
+	assert.NoError(t, WriteFile(dir, "secret", []byte("old"), 0644, WriteOptions{}))
+
+	// Test a cross-device rename is reported clearly and cleaned up
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	err = WriteFile(dir, "secret", []byte("new"), 0644, WriteOptions{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "different filesystems")
+	content, err := ioutil.ReadFile(path.Join(dir, "secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, "old", string(content))
+	assert.Equal(t, []string{"secret"}, dirNames(t, dir))
+}
+
+func TestWriteFileLongPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Test a file name at the element limit is written
+	name := strings.Repeat("a", maxNameLen)
+	assert.NoError(t, WriteFile(dir, name, []byte("value"), 0644, WriteOptions{}))
+
+	// Test a file name above the element limit is rejected by name
+	name = strings.Repeat("a", maxNameLen+1)
+	err = WriteFile(dir, name, []byte("value"), 0644, WriteOptions{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeding the limit")
+
+	// Test a path above the total limit is rejected
+	name = strings.Repeat("a/", maxPathLen/2)
+	err = WriteFile(dir, name+"secret", []byte("value"), 0644, WriteOptions{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeding the limit")
+}
+
+func TestWriteFileTypeMismatch(t *testing.T) {
+	// Test the error policy, also used by default, leaves mismatches in place
+	for _, policy := range []string{"", TypeMismatchError} {
+		dir, err := ioutil.TempDir("", "secrets-store")
+		assert.NoError(t, err)
+		defer os.RemoveAll(dir)
+		opts := WriteOptions{TypeMismatch: policy}
+
+		// Test a directory where a file should go
+		assert.NoError(t, os.Mkdir(path.Join(dir, "secret"), 0755))
+		err = WriteFile(dir, "secret", []byte("value"), 0644, opts)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is a directory, expected a file")
+		info, err := os.Stat(path.Join(dir, "secret"))
+		assert.NoError(t, err)
+		assert.True(t, info.IsDir())
+
+		// Test a file where a directory should go
+		assert.NoError(t, WriteFile(dir, "parent", []byte("value"), 0644, opts))
+		err = WriteFile(dir, "parent/secret", []byte("value"), 0644, opts)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is a file, expected a directory")
+
+		err = WriteFile(dir, "parent/nested/secret", []byte("value"), 0644, opts)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is a file, expected a directory")
+		content, err := ioutil.ReadFile(path.Join(dir, "parent"))
+		assert.NoError(t, err)
+		assert.Equal(t, "value", string(content))
+	}
+}
+
+func TestWriteFileTypeMismatchReplace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	opts := WriteOptions{TypeMismatch: TypeMismatchReplace}
+
+	// Test a directory where a file should go is replaced with the file
+	assert.NoError(t, os.MkdirAll(path.Join(dir, "secret", "nested"), 0755))
+	assert.NoError(t, WriteFile(dir, "secret", []byte("value"), 0644, opts))
+	content, err := ioutil.ReadFile(path.Join(dir, "secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, "value", string(content))
+
+	// Test a file where a directory should go is replaced with the directory
+	assert.NoError(t, WriteFile(dir, "parent", []byte("value"), 0644, opts))
+	assert.NoError(t, WriteFile(dir, "parent/secret", []byte("value"), 0644, opts))
+	content, err = ioutil.ReadFile(path.Join(dir, "parent", "secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, "value", string(content))
+
+	assert.NoError(t, WriteFile(dir, "other", []byte("value"), 0644, opts))
+	assert.NoError(t, WriteFile(dir, "other/nested/secret", []byte("value"), 0644, opts))
+	content, err = ioutil.ReadFile(path.Join(dir, "other", "nested", "secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, "value", string(content))
+	assert.Equal(t, []string{"other", "parent", "secret"}, dirNames(t, dir))
+
+	// Test entries outside the target path are never replaced
+	outside, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(outside)
+	assert.NoError(t, ioutil.WriteFile(path.Join(outside, "file"), []byte("kept"), 0644))
+	rel := path.Join("..", path.Base(outside), "file", "secret")
+	err = WriteFile(dir, rel, []byte("value"), 0644, opts)
+	assert.Error(t, err)
+	content, err = ioutil.ReadFile(path.Join(outside, "file"))
+	assert.NoError(t, err)
+	assert.Equal(t, "kept", string(content))
+}
+
+func TestWriteFileMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Test the requested mode is kept under a restrictive umask
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+	assert.NoError(t, WriteFile(dir, "secret", []byte("value"), 0644, WriteOptions{}))
+	info, err := os.Stat(path.Join(dir, "secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// Test the requested mode is not widened under a permissive umask,
+	// including when an existing file had a wider mode
+	syscall.Umask(0)
+	assert.NoError(t, WriteFile(dir, "secret", []byte("value"), 0400, WriteOptions{}))
+	info, err = os.Stat(path.Join(dir, "secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0400), info.Mode().Perm())
+}
+
+// longDir returns an absolute directory path of exactly n bytes whose elements
+// stay within maxNameLen
+func longDir(n int) string {
+	dir := ""
+	for len(dir) < n {
+		elem := n - len(dir) - 1
+		if elem > maxNameLen {
+			elem = maxNameLen
+		}
+		dir += "/" + strings.Repeat("a", elem)
+	}
+	return dir
+}
+
+func TestCheckPathLengthTemporaryFile(t *testing.T) {
+	// Test the longest directory whose temporary file still fits
+	dir := longDir(maxPathLen - 1 - maxTmpNameLen)
+	assert.NoError(t, checkPathLength(dir+"/secret"))
+
+	// Test a directory where the file fits but its temporary file does not
+	dir = longDir(maxPathLen - maxTmpNameLen)
+	assert.True(t, len(dir+"/secret") <= maxPathLen)
+	err := checkPathLength(dir + "/secret")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "temporary file path")
+}
+
+func TestWrapWriteError(t *testing.T) {
+	err := wrapWriteError(&os.PathError{Op: "open", Path: "/fake/secret", Err: syscall.EROFS}, "secret", "/fake")
+	assert.Equal(t, ErrTargetReadOnly, errors.Cause(err))
+	assert.Contains(t, err.Error(), "secret")
+
+	err = wrapWriteError(&os.PathError{Op: "write", Path: "/fake/secret", Err: syscall.ENOSPC}, "secret", "/fake")
+	assert.Equal(t, ErrNoSpace, errors.Cause(err))
+
+	err = wrapWriteError(&os.LinkError{Op: "rename", Old: "/fake/.secret.1", New: "/fake/secret", Err: syscall.EROFS}, "secret", "/fake")
+	assert.Equal(t, ErrTargetReadOnly, errors.Cause(err))
+
+	pe := &os.PathError{Op: "open", Path: "/fake/secret", Err: syscall.EACCES}
+	err = wrapWriteError(pe, "secret", "/fake")
+	assert.Equal(t, pe, errors.Cause(err))
+}
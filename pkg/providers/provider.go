@@ -3,9 +3,29 @@ package providers
 import (
 	"os"
 
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 )
 
+// Config holds driver settings applied by every provider.
+type Config struct {
+	// RedactedAttributes are attributes whose values providers must not log
+	RedactedAttributes []string
+	// Write configures the checks applied when writing secret files
+	Write WriteOptions
+	// MaxObjects is the most objects a single mount may contain, unlimited if 0
+	MaxObjects int
+}
+
+// CheckObjectCount returns a descriptive error if a mount of count objects
+// exceeds MaxObjects. Providers call it before fetching or writing anything.
+func (c Config) CheckObjectCount(count int) error {
+	if c.MaxObjects > 0 && count > c.MaxObjects {
+		return errors.Errorf("%d objects requested, exceeding the limit of %d objects per mount", count, c.MaxObjects)
+	}
+	return nil
+}
+
 // Provider contains the methods required to implement a SecretsStore csi provider.
 type Provider interface {
 	// MountSecretsStoreObjectContent mounts content of the secrets store object to target path
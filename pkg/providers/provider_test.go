@@ -0,0 +1,19 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckObjectCount(t *testing.T) {
+	// Test the count is unlimited by default
+	assert.NoError(t, Config{}.CheckObjectCount(10000))
+
+	// Test a count at the limit is allowed and one above is rejected
+	cfg := Config{MaxObjects: 2}
+	assert.NoError(t, cfg.CheckObjectCount(2))
+	err := cfg.CheckObjectCount(3)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "3 objects requested, exceeding the limit of 2")
+}
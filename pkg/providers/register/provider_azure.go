@@ -12,5 +12,5 @@ func init() {
 }
 
 func initAzure(cfg InitConfig) (providers.Provider, error) {
-	return azure.NewProvider()
+	return azure.NewProvider(cfg.Config)
 }
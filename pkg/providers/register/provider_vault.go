@@ -12,5 +12,5 @@ func init() {
 }
 
 func initVault(cfg InitConfig) (providers.Provider, error) {
-	return vault.NewProvider()
+	return vault.NewProvider(cfg.Config)
 }
@@ -10,6 +10,8 @@ var providerInits = make(map[string]initFunc)
 // InitConfig is the config passed to initialize a registered provider.
 type InitConfig struct {
 	Name string
+	// Config holds driver settings applied by the provider
+	Config providers.Config
 }
 
 type initFunc func(InitConfig) (providers.Provider, error)
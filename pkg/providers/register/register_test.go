@@ -0,0 +1,112 @@
+package register
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/deislabs/secrets-store-csi-driver/pkg/providers"
+	"github.com/golang/glog"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+// captureLogs returns what glog writes at verbosity 5 while f runs
+func captureLogs(t *testing.T, f func()) string {
+	for name, value := range map[string]string{"logtostderr": "true", "v": "5"} {
+		old := flag.Lookup(name).Value.String()
+		assert.NoError(t, flag.Set(name, value))
+		defer flag.Set(name, old)
+	}
+	out, err := ioutil.TempFile("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	stderr := os.Stderr
+	os.Stderr = out
+	f()
+	glog.Flush()
+	os.Stderr = stderr
+
+	logs, err := ioutil.ReadFile(out.Name())
+	assert.NoError(t, err)
+	return string(logs)
+}
+
+// testAttribs are attributes for each provider requesting two objects, with
+// which mounts fail once the provider tries to authenticate
+var testAttribs = map[string]map[string]string{
+	"azure": {
+		"keyvaultName":   "fakevault",
+		"resourceGroup":  "fakegroup",
+		"subscriptionId": "fakesubscription",
+		"tenantId":       "faketenant",
+		"usePodIdentity": "true",
+		"objects":        "array:\n  - |\n    objectName: fakeobject\n    objectType: secret\n  - |\n    objectName: otherobject\n    objectType: secret\n",
+	},
+	"vault": {
+		"roleName":                          "fakerole",
+		"vaultAddress":                      "https://fakevault:8200",
+		"vaultKubernetesServiceAccountPath": "/nonexistent/token",
+		"objects":                           "array:\n  - |\n    objectPath: /fakeobject\n    objectName: fakeobject\n  - |\n    objectPath: /otherobject\n    objectName: otherobject\n",
+	},
+}
+
+func TestProviderLogsRedactedAttributes(t *testing.T) {
+	for provider, attrib := range testAttribs {
+		if _, ok := providerInits[provider]; !ok {
+			continue
+		}
+		var keys []string
+		for k := range attrib {
+			keys = append(keys, k)
+		}
+		p, err := GetProvider(provider, InitConfig{Config: providers.Config{RedactedAttributes: keys}})
+		assert.NoError(t, err)
+
+		dir, err := ioutil.TempDir("", "secrets-store")
+		assert.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		logs := captureLogs(t, func() {
+			assert.Error(t, p.MountSecretsStoreObjectContent(context.Background(), attrib, nil, dir, 0644))
+		})
+		for k, v := range attrib {
+			if k == "usePodIdentity" {
+				continue
+			}
+			assert.NotContains(t, logs, v, "%s logged the value of %s", provider, k)
+		}
+		assert.Contains(t, logs, "[REDACTED]", provider)
+	}
+}
+
+func TestProviderMaxObjects(t *testing.T) {
+	for provider, attrib := range testAttribs {
+		if _, ok := providerInits[provider]; !ok {
+			continue
+		}
+		dir, err := ioutil.TempDir("", "secrets-store")
+		assert.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		// Test a mount at the limit gets past the check
+		p, err := GetProvider(provider, InitConfig{Config: providers.Config{MaxObjects: 2}})
+		assert.NoError(t, err)
+		err = p.MountSecretsStoreObjectContent(context.Background(), attrib, nil, dir, 0644)
+		assert.Error(t, err)
+		assert.NotContains(t, err.Error(), "exceeding the limit", provider)
+
+		// Test a mount above the limit is rejected before anything is written
+		p, err = GetProvider(provider, InitConfig{Config: providers.Config{MaxObjects: 1}})
+		assert.NoError(t, err)
+		err = p.MountSecretsStoreObjectContent(context.Background(), attrib, nil, dir, 0644)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "2 objects requested, exceeding the limit of 1", provider)
+		files, err := ioutil.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, files, provider)
+	}
+}
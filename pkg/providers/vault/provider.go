@@ -10,7 +10,6 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
-	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -18,6 +17,8 @@ import (
 	"golang.org/x/net/context"
 	yaml "gopkg.in/yaml.v2"
 
+	csicommon "github.com/deislabs/secrets-store-csi-driver/pkg/csi-common"
+	"github.com/deislabs/secrets-store-csi-driver/pkg/providers"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	"golang.org/x/net/http2"
@@ -43,6 +44,9 @@ type Provider struct {
 	VaultServerName              string
 	VaultK8SMountPath            string
 	KubernetesServiceAccountPath string
+
+	// driver settings applied by the provider
+	config providers.Config
 }
 
 // KeyValueObject is the object stored in Vault's Key-Value store.
@@ -60,9 +64,9 @@ type StringArray struct {
 }
 
 // NewProvider creates a new provider HashiCorp Vault.
-func NewProvider() (*Provider, error) {
+func NewProvider(config providers.Config) (*Provider, error) {
 	glog.V(2).Infof("NewProvider")
-	var p Provider
+	p := Provider{config: config}
 	return &p, nil
 }
 
@@ -120,8 +124,6 @@ func (p *Provider) login(jwt string, roleName string) (string, error) {
 	addr := p.VaultAddress + "/v1/auth/" + p.VaultK8SMountPath + "/login"
 	body := fmt.Sprintf(`{"role": "%s", "jwt": "%s"}`, roleName, jwt)
 
-	glog.V(2).Infof("vault: vault address: %s\n", addr)
-
 	req, err := http.NewRequest(http.MethodPost, addr, strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	if err != nil {
@@ -279,14 +281,16 @@ func (p *Provider) MountSecretsStoreObjectContent(ctx context.Context, attrib ma
 		return errors.Errorf("missing vault role name. please specify 'roleName' in pv definition.")
 	}
 	p.VaultRole = roleName
+	logAttrib := csicommon.RedactKeys(attrib, p.config.RedactedAttributes...)
 
-	glog.V(2).Infof("vault: roleName %s", p.VaultRole)
+	glog.V(2).Infof("vault: roleName %s", logAttrib["roleName"])
 
 	p.VaultAddress = attrib["vaultAddress"]
 	if p.VaultAddress == "" {
 		p.VaultAddress = defaultVaultAddress
+		logAttrib["vaultAddress"] = defaultVaultAddress
 	}
-	glog.V(2).Infof("vault: vault address %s", p.VaultAddress)
+	glog.V(2).Infof("vault: vault address %s", logAttrib["vaultAddress"])
 
 	// One of the following variables should be set when vaultSkipTLSVerify is false.
 	// Otherwise, system certificates are used to make requests to vault.
@@ -316,7 +320,7 @@ func (p *Provider) MountSecretsStoreObjectContent(ctx context.Context, attrib ma
 
 	keyValueObjects := []KeyValueObject{}
 	objectsStrings := attrib["objects"]
-	fmt.Printf("objectsStrings: [%s]\n", objectsStrings)
+	glog.V(2).Infof("vault: objects %s", logAttrib["objects"])
 
 	var objects StringArray
 	err = yaml.Unmarshal([]byte(objectsStrings), &objects)
@@ -324,9 +328,10 @@ func (p *Provider) MountSecretsStoreObjectContent(ctx context.Context, attrib ma
 		fmt.Printf("unmarshall failed for objects")
 		return err
 	}
-	fmt.Printf("objects: [%v]", objects.Array)
+	if err := p.config.CheckObjectCount(len(objects.Array)); err != nil {
+		return err
+	}
 	for _, object := range objects.Array {
-		fmt.Printf("unmarshal object: [%s]\n", object)
 		var keyValueObject KeyValueObject
 		err = yaml.Unmarshal([]byte(object), &keyValueObject)
 		if err != nil {
@@ -343,8 +348,8 @@ func (p *Provider) MountSecretsStoreObjectContent(ctx context.Context, attrib ma
 			return err
 		}
 		objectContent := []byte(content)
-		if err := ioutil.WriteFile(path.Join(targetPath, keyValueObject.ObjectPath), objectContent, permission); err != nil {
-			return errors.Wrapf(err, "secrets-store csi driver failed to write %s at %s", keyValueObject.ObjectPath, targetPath)
+		if err := providers.WriteFile(targetPath, keyValueObject.ObjectPath, objectContent, permission, p.config.Write); err != nil {
+			return err
 		}
 		glog.V(0).Infof("secrets-store csi driver wrote %s at %s", keyValueObject.ObjectPath, targetPath)
 	}
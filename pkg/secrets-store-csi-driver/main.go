@@ -19,9 +19,11 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	"github.com/golang/glog"
 
+	"github.com/deislabs/secrets-store-csi-driver/pkg/providers"
 	secretsstore "github.com/deislabs/secrets-store-csi-driver/pkg/secrets-store"
 )
 
@@ -33,9 +35,18 @@ func init() {
 }
 
 var (
-	endpoint   = flag.String("endpoint", "unix://tmp/csi.sock", "CSI endpoint")
-	driverName = flag.String("drivername", "secrets-store.csi.k8s.com", "name of the driver")
-	nodeID     = flag.String("nodeid", "", "node id")
+	endpoint           = flag.String("endpoint", "unix://tmp/csi.sock", "CSI endpoint")
+	driverName         = flag.String("drivername", "secrets-store.csi.k8s.com", "name of the driver")
+	nodeID             = flag.String("nodeid", "", "node id")
+	tmpfsCheck         = flag.String("tmpfs-check", secretsstore.TmpfsCheckNone, "verify the target path is backed by tmpfs before writing secrets: none, warn or error")
+	redactedAttributes = flag.String("redact-attributes", "", "comma separated volume attributes whose values are redacted from driver and provider logs")
+	readyFile          = flag.String("ready-file", "", "name of an empty file written to the target path once all secrets are written, reserved so a secret with the same name fails the mount, disabled if empty")
+	maxMounts          = flag.Int("max-concurrent-mounts", 0, "maximum number of volumes mounted concurrently across all providers, unlimited if 0")
+	maxFileSize        = flag.Int64("max-file-size", 0, "maximum size in bytes of a single secret file, unlimited if 0")
+	maxObjects         = flag.Int("max-objects", 1000, "maximum number of objects a single volume may contain, unlimited if 0")
+	rejectEmptyFiles   = flag.Bool("reject-empty-files", false, "fail mounts where a provider returns empty content for a secret file")
+	typeMismatch       = flag.String("type-mismatch", providers.TypeMismatchError, "action when a directory exists where a secret file should be written, or a file where a directory should be: error or replace")
+	gracefulShutdown   = flag.Bool("graceful-shutdown", false, "stop gracefully on SIGTERM or SIGINT, waiting for in-flight requests")
 )
 
 func main() {
@@ -47,5 +58,19 @@ func main() {
 
 func handle() {
 	driver := secretsstore.GetDriver()
-	driver.Run(*driverName, *nodeID, *endpoint)
+	var redacted []string
+	if *redactedAttributes != "" {
+		redacted = strings.Split(*redactedAttributes, ",")
+	}
+	driver.Run(*driverName, *nodeID, *endpoint, secretsstore.Options{
+		TmpfsCheck:          *tmpfsCheck,
+		RedactedAttributes:  redacted,
+		ReadyFile:           *readyFile,
+		MaxConcurrentMounts: *maxMounts,
+		MaxFileSize:         *maxFileSize,
+		MaxObjects:          *maxObjects,
+		RejectEmptyFiles:    *rejectEmptyFiles,
+		TypeMismatch:        *typeMismatch,
+		GracefulShutdown:    *gracefulShutdown,
+	})
 }
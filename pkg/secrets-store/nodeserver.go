@@ -27,6 +27,7 @@ import (
 	"github.com/deislabs/secrets-store-csi-driver/pkg/providers"
 	"github.com/deislabs/secrets-store-csi-driver/pkg/providers/register"
 	"github.com/golang/glog"
+	"github.com/pkg/errors"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -37,6 +38,9 @@ import (
 
 type nodeServer struct {
 	*csicommon.DefaultNodeServer
+	opts Options
+	// mountSlots bounds concurrent mounts across all providers, nil if unbounded
+	mountSlots chan struct{}
 }
 
 const (
@@ -59,6 +63,12 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		return nil, status.Error(codes.InvalidArgument, "Volume attributes missing in request")
 	}
 
+	release, err := ns.acquireMountSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	targetPath := req.GetTargetPath()
 	notMnt, err := mount.New("").IsLikelyNotMountPoint(targetPath)
 	if err != nil && !os.IsNotExist(err) {
@@ -82,10 +92,10 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	attrib := req.GetVolumeContext()
 	mountFlags := req.GetVolumeCapability().GetMount().GetMountFlags()
 
-	glog.V(5).Infof("target %v\nvolumeId %v\nattributes %v\nmountflags %v\n",
-		targetPath, volumeID, attrib, mountFlags)
-
 	secrets := req.GetSecrets()
+	glog.V(5).Infof("target %v\nvolumeId %v\nattributes %v\nsecrets %v\nmountflags %v\n",
+		targetPath, volumeID, csicommon.RedactKeys(attrib, ns.opts.RedactedAttributes...), csicommon.RedactAll(secrets), mountFlags)
+
 	providerName := attrib["providerName"]
 	if providerName == "" {
 		return nil, fmt.Errorf("providerName is not set")
@@ -106,7 +116,9 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	}
 
 	var provider providers.Provider
-	initConfig := register.InitConfig{}
+	initConfig := register.InitConfig{
+		Config: ns.providerConfig(),
+	}
 	provider, err = register.GetProvider(providerName, initConfig)
 	if err != nil {
 		return nil, fmt.Errorf("Error initializing provider: %s", err)
@@ -117,8 +129,11 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		glog.V(0).Infof("mount err: %v", err)
 		return nil, err
 	}
-	err = provider.MountSecretsStoreObjectContent(ctx, attrib, secrets, targetPath, permission)
-	if err != nil {
+	if err := checkTmpfs(targetPath, ns.opts.TmpfsCheck); err != nil {
+		mounter.Unmount(targetPath)
+		return nil, err
+	}
+	if err := ns.mountContent(ctx, provider, attrib, secrets, targetPath); err != nil {
 		mounter.Unmount(targetPath)
 		return nil, err
 	}
@@ -130,6 +145,70 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
+// providerConfig returns the driver settings passed to providers
+func (ns *nodeServer) providerConfig() providers.Config {
+	cfg := providers.Config{
+		RedactedAttributes: ns.opts.RedactedAttributes,
+		Write: providers.WriteOptions{
+			MaxFileSize:  ns.opts.MaxFileSize,
+			RejectEmpty:  ns.opts.RejectEmptyFiles,
+			TypeMismatch: ns.opts.TypeMismatch,
+		},
+		MaxObjects: ns.opts.MaxObjects,
+	}
+	if ns.opts.ReadyFile != "" {
+		// all volumes share a directory, so a secret under the ready file
+		// name is rejected by name rather than detected after the fact
+		cfg.Write.ReservedNames = []string{ns.opts.ReadyFile}
+	}
+	return cfg
+}
+
+// mountContent has provider write secrets to targetPath, followed by the ready
+// file once all of them are written.
+func (ns *nodeServer) mountContent(ctx context.Context, provider providers.Provider, attrib map[string]string, secrets map[string]string, targetPath string) error {
+	if err := provider.MountSecretsStoreObjectContent(ctx, attrib, secrets, targetPath, permission); err != nil {
+		return writeStatusError(err)
+	}
+	if err := writeReadyFile(targetPath, ns.opts.ReadyFile, permission); err != nil {
+		return writeStatusError(err)
+	}
+	return nil
+}
+
+// writeStatusError maps write failures caused by the node's filesystem to gRPC
+// codes, so the kubelet reports them as node issues rather than as Unknown.
+// Secrets colliding with a reserved name are reported as invalid arguments.
+func writeStatusError(err error) error {
+	switch errors.Cause(err) {
+	case providers.ErrReservedName:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case providers.ErrTargetReadOnly:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case providers.ErrNoSpace:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return err
+}
+
+// acquireMountSlot waits until fewer than the configured number of mounts are
+// in progress, or ctx is done. The returned func releases the slot.
+func (ns *nodeServer) acquireMountSlot(ctx context.Context) (func(), error) {
+	if ns.mountSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case ns.mountSlots <- struct{}{}:
+		return func() { <-ns.mountSlots }, nil
+	case <-ctx.Done():
+		code := codes.Canceled
+		if ctx.Err() == context.DeadlineExceeded {
+			code = codes.DeadlineExceeded
+		}
+		return nil, status.Errorf(code, "waiting for a mount slot: %v", ctx.Err())
+	}
+}
+
 func (ns *nodeServer) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
 	glog.V(0).Infof("NodeUnpublishVolume")
 	// Check arguments
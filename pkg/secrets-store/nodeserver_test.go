@@ -0,0 +1,153 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretsstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/deislabs/secrets-store-csi-driver/pkg/providers"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAcquireMountSlot(t *testing.T) {
+	// Test unbounded node server never waits
+	ns := newNodeServer(nil, Options{})
+	for i := 0; i < 3; i++ {
+		_, err := ns.acquireMountSlot(context.Background())
+		assert.NoError(t, err)
+	}
+
+	ns = newNodeServer(nil, Options{MaxConcurrentMounts: 1})
+	release, err := ns.acquireMountSlot(context.Background())
+	assert.NoError(t, err)
+
+	// Test a second mount waits until its context expires
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = ns.acquireMountSlot(ctx)
+	s, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, s.Code(), codes.DeadlineExceeded)
+
+	// Test a waiting mount proceeds once the slot is released
+	acquired := make(chan struct{})
+	go func() {
+		r, err := ns.acquireMountSlot(context.Background())
+		assert.NoError(t, err)
+		r()
+		close(acquired)
+	}()
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("mount slot not acquired after release")
+	}
+}
+
+func TestWriteStatusError(t *testing.T) {
+	err := writeStatusError(errors.Wrap(providers.ErrTargetReadOnly, "failed to write secret"))
+	s, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, s.Code(), codes.FailedPrecondition)
+	assert.Contains(t, s.Message(), "failed to write secret")
+
+	err = writeStatusError(errors.Wrap(providers.ErrNoSpace, "failed to write secret"))
+	s, ok = status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, s.Code(), codes.ResourceExhausted)
+
+	err = writeStatusError(errors.Wrap(providers.ErrReservedName, "failed to write ..ready"))
+	s, ok = status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, s.Code(), codes.InvalidArgument)
+
+	// Test other errors are returned unchanged
+	other := errors.New("failed to get secret")
+	assert.Equal(t, other, writeStatusError(other))
+}
+
+// fakeProvider writes files the way providers do, optionally pausing after the
+// first one until resume is closed
+type fakeProvider struct {
+	config providers.Config
+	files  []string
+	paused chan struct{}
+	resume chan struct{}
+}
+
+func (p *fakeProvider) MountSecretsStoreObjectContent(ctx context.Context, attrib map[string]string, secrets map[string]string, targetPath string, permission os.FileMode) error {
+	for i, file := range p.files {
+		if err := providers.WriteFile(targetPath, file, []byte(file), permission, p.config.Write); err != nil {
+			return err
+		}
+		if i == 0 && p.paused != nil {
+			close(p.paused)
+			<-p.resume
+		}
+	}
+	return nil
+}
+
+func TestMountContentReadyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ns := newNodeServer(nil, Options{ReadyFile: "..ready"})
+	cfg := ns.providerConfig()
+
+	// Test a publish completing while another is writing secrets to the shared
+	// target fails neither of them
+	a := &fakeProvider{config: cfg, files: []string{"a1", "a2"}, paused: make(chan struct{}), resume: make(chan struct{})}
+	b := &fakeProvider{config: cfg, files: []string{"b1"}}
+	errs := make(chan error)
+	go func() {
+		errs <- ns.mountContent(context.Background(), a, nil, nil, dir)
+	}()
+	<-a.paused
+	assert.NoError(t, ns.mountContent(context.Background(), b, nil, nil, dir))
+	close(a.resume)
+	assert.NoError(t, <-errs)
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	assert.Equal(t, []string{"..ready", "a1", "a2", "b1"}, names)
+
+	// Test a secret under the ready file name fails the mount and leaves the
+	// ready file empty
+	c := &fakeProvider{config: cfg, files: []string{"..ready"}}
+	err = ns.mountContent(context.Background(), c, nil, nil, dir)
+	s, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, s.Code())
+	info, err := os.Stat(path.Join(dir, "..ready"))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretsstore
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/deislabs/secrets-store-csi-driver/pkg/providers"
+)
+
+// validReadyFile returns an error if name cannot be used as a readiness file
+// directly under the target path
+func validReadyFile(name string) error {
+	if name == "" {
+		return nil
+	}
+	if name == "." || name == ".." || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid ready file name %q, should be a plain file name", name)
+	}
+	return nil
+}
+
+// writeReadyFile writes the empty readiness file name under targetPath once all
+// secrets have been written, so consuming apps can wait for it to appear.
+// Providers are kept from writing the same name by providerConfig.
+func writeReadyFile(targetPath string, name string, permission os.FileMode) error {
+	if name == "" {
+		return nil
+	}
+	return providers.WriteFile(targetPath, name, []byte{}, permission, providers.WriteOptions{})
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretsstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidReadyFile(t *testing.T) {
+	assert.NoError(t, validReadyFile(""))
+	assert.NoError(t, validReadyFile("..ready"))
+	assert.Error(t, validReadyFile("."))
+	assert.Error(t, validReadyFile(".."))
+	assert.Error(t, validReadyFile("../ready"))
+	assert.Error(t, validReadyFile("dir/ready"))
+}
+
+func TestWriteReadyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets-store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Test no file is written when disabled
+	assert.NoError(t, writeReadyFile(dir, "", permission))
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	assert.NoError(t, writeReadyFile(dir, "..ready", permission))
+	info, err := os.Stat(path.Join(dir, "..ready"))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	// Test the ready file is recreated on subsequent writes
+	assert.NoError(t, writeReadyFile(dir, "..ready", permission))
+	info, err = os.Stat(path.Join(dir, "..ready"))
+	assert.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
@@ -17,9 +17,15 @@ limitations under the License.
 package secretsstore
 
 import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/container-storage-interface/spec/lib/go/csi"
 
 	csicommon "github.com/deislabs/secrets-store-csi-driver/pkg/csi-common"
+	"github.com/deislabs/secrets-store-csi-driver/pkg/providers"
 	"github.com/golang/glog"
 )
 
@@ -49,16 +55,74 @@ func GetDriver() *SecretsStore {
 	return &SecretsStore{}
 }
 
-func newNodeServer(d *csicommon.CSIDriver) *nodeServer {
-	return &nodeServer{
+// Options configures optional driver behavior. The zero value keeps every
+// optional feature disabled.
+type Options struct {
+	// TmpfsCheck controls verification that target paths are backed by tmpfs
+	TmpfsCheck string
+	// RedactedAttributes are volume attributes whose values are redacted from
+	// driver and provider logs
+	RedactedAttributes []string
+	// ReadyFile is written to the target path after all secrets are written, if set
+	ReadyFile string
+	// MaxConcurrentMounts bounds concurrent mounts across all providers, unbounded if 0
+	MaxConcurrentMounts int
+	// MaxFileSize is the largest secret file in bytes a provider may write, unlimited if 0
+	MaxFileSize int64
+	// MaxObjects is the most objects a single mount may contain, unlimited if 0
+	MaxObjects int
+	// RejectEmptyFiles fails mounts where a provider returns empty content
+	RejectEmptyFiles bool
+	// TypeMismatch is the policy when a secret file and an existing directory
+	// are swapped, see providers.TypeMismatchError and TypeMismatchReplace
+	TypeMismatch string
+	// GracefulShutdown stops the server gracefully on SIGTERM or SIGINT
+	GracefulShutdown bool
+}
+
+// validTypeMismatch returns an error if policy is not a supported type mismatch policy
+func validTypeMismatch(policy string) error {
+	switch policy {
+	case "", providers.TypeMismatchError, providers.TypeMismatchReplace:
+		return nil
+	}
+	return fmt.Errorf("invalid type mismatch policy %q, should be %s or %s", policy, providers.TypeMismatchError, providers.TypeMismatchReplace)
+}
+
+func newNodeServer(d *csicommon.CSIDriver, opts Options) *nodeServer {
+	ns := &nodeServer{
 		DefaultNodeServer: csicommon.NewDefaultNodeServer(d),
+		opts:              opts,
+	}
+	if opts.MaxConcurrentMounts > 0 {
+		ns.mountSlots = make(chan struct{}, opts.MaxConcurrentMounts)
 	}
+	return ns
 }
 
-func (s *SecretsStore) Run(driverName, nodeID, endpoint string) {
+func (s *SecretsStore) Run(driverName, nodeID, endpoint string, opts Options) {
 	glog.Infof("Driver: %v ", driverName)
 	glog.Infof("Version: %s", vendorVersion)
 
+	if err := validTmpfsCheck(opts.TmpfsCheck); err != nil {
+		glog.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := validReadyFile(opts.ReadyFile); err != nil {
+		glog.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := validTypeMismatch(opts.TypeMismatch); err != nil {
+		glog.Fatalf("Invalid configuration: %v", err)
+	}
+	if opts.MaxConcurrentMounts < 0 {
+		glog.Fatalf("Invalid configuration: max concurrent mounts %d is negative", opts.MaxConcurrentMounts)
+	}
+	if opts.MaxFileSize < 0 {
+		glog.Fatalf("Invalid configuration: max file size %d is negative", opts.MaxFileSize)
+	}
+	if opts.MaxObjects < 0 {
+		glog.Fatalf("Invalid configuration: max objects %d is negative", opts.MaxObjects)
+	}
+
 	// Initialize default library driver
 	s.driver = csicommon.NewCSIDriver(driverName, vendorVersion, nodeID)
 	if s.driver == nil {
@@ -73,9 +137,27 @@ func (s *SecretsStore) Run(driverName, nodeID, endpoint string) {
 		csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY,
 	})
 
-	s.ns = newNodeServer(s.driver)
+	s.ns = newNodeServer(s.driver, opts)
 
-	server := csicommon.NewNonBlockingGRPCServer()
+	server := csicommon.NewNonBlockingGRPCServer(opts.RedactedAttributes...)
 	server.Start(endpoint, csicommon.NewDefaultIdentityServer(s.driver), csicommon.NewDefaultControllerServer(s.driver), s.ns)
+	if opts.GracefulShutdown {
+		// left opt-in so programs embedding the driver keep control of signal handling
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+		go stopOnSignal(sigs, server.Stop, server.ForceStop)
+	}
 	server.Wait()
 }
+
+// stopOnSignal waits for a signal on sigs and calls stop, letting in-flight
+// requests finish before the server exits. Since a graceful stop can block on
+// a hung provider, a second signal calls forceStop.
+func stopOnSignal(sigs <-chan os.Signal, stop func(), forceStop func()) {
+	sig := <-sigs
+	glog.Infof("Received %v, stopping gracefully", sig)
+	go stop()
+	sig = <-sigs
+	glog.Infof("Received %v, stopping immediately", sig)
+	forceStop()
+}
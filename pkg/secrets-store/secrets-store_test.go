@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretsstore
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/deislabs/secrets-store-csi-driver/pkg/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStopOnSignal(t *testing.T) {
+	sigs := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	forceStopped := make(chan struct{})
+	go stopOnSignal(sigs, func() { close(stopped) }, func() { close(forceStopped) })
+
+	// Test stop is not called before a signal arrives
+	select {
+	case <-stopped:
+		t.Fatal("stop called without a signal")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	sigs <- syscall.SIGTERM
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop not called after SIGTERM")
+	}
+
+	// Test a second signal forces the stop
+	select {
+	case <-forceStopped:
+		t.Fatal("force stop called after a single signal")
+	case <-time.After(10 * time.Millisecond):
+	}
+	sigs <- syscall.SIGINT
+	select {
+	case <-forceStopped:
+	case <-time.After(time.Second):
+		t.Fatal("force stop not called after a second signal")
+	}
+	assert.Empty(t, sigs)
+}
+
+func TestValidTypeMismatch(t *testing.T) {
+	assert.NoError(t, validTypeMismatch(""))
+	assert.NoError(t, validTypeMismatch(providers.TypeMismatchError))
+	assert.NoError(t, validTypeMismatch(providers.TypeMismatchReplace))
+	assert.Error(t, validTypeMismatch("ignore"))
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretsstore
+
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Modes for verifying that secrets are written to a tmpfs-backed target path
+const (
+	// TmpfsCheckNone disables the check
+	TmpfsCheckNone = "none"
+	// TmpfsCheckWarn logs a warning when the target path is not on tmpfs
+	TmpfsCheckWarn = "warn"
+	// TmpfsCheckError fails the mount when the target path is not on tmpfs
+	TmpfsCheckError = "error"
+)
+
+// tmpfsMagic is the filesystem type statfs(2) reports for tmpfs
+const tmpfsMagic int64 = 0x01021994
+
+// fsType returns the statfs filesystem type of path, overridden in tests
+var fsType = statfsType
+
+// validTmpfsCheck returns an error if mode is not a supported tmpfs check mode
+func validTmpfsCheck(mode string) error {
+	switch mode {
+	case "", TmpfsCheckNone, TmpfsCheckWarn, TmpfsCheckError:
+		return nil
+	}
+	return fmt.Errorf("invalid tmpfs check mode %q, should be %s, %s or %s", mode, TmpfsCheckNone, TmpfsCheckWarn, TmpfsCheckError)
+}
+
+// checkTmpfs verifies that targetPath is backed by tmpfs so that secrets are
+// never persisted to disk. Depending on mode a mismatch is ignored, logged or
+// returned as an error.
+func checkTmpfs(targetPath string, mode string) error {
+	if mode == "" || mode == TmpfsCheckNone {
+		return nil
+	}
+	t, err := fsType(targetPath)
+	if err != nil {
+		if mode == TmpfsCheckWarn {
+			glog.Warningf("secrets-store - unable to determine filesystem of %s: %v", targetPath, err)
+			return nil
+		}
+		return status.Errorf(codes.Internal, "unable to determine filesystem of %s: %v", targetPath, err)
+	}
+	if t == tmpfsMagic {
+		return nil
+	}
+	if mode == TmpfsCheckWarn {
+		glog.Warningf("secrets-store - %s is not on tmpfs (type 0x%x), secrets may be persisted to disk", targetPath, t)
+		return nil
+	}
+	return status.Errorf(codes.FailedPrecondition, "%s is not on tmpfs (type 0x%x), refusing to write secrets", targetPath, t)
+}
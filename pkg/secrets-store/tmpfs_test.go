@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretsstore
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func fakeFSType(t int64, err error) func(string) (int64, error) {
+	return func(string) (int64, error) {
+		return t, err
+	}
+}
+
+func TestCheckTmpfs(t *testing.T) {
+	defer func() { fsType = statfsType }()
+
+	// Test tmpfs target path passes in every mode
+	fsType = fakeFSType(tmpfsMagic, nil)
+	for _, mode := range []string{"", TmpfsCheckNone, TmpfsCheckWarn, TmpfsCheckError} {
+		assert.NoError(t, checkTmpfs("/fake", mode))
+	}
+
+	// Test disk-backed target path (ext4)
+	fsType = fakeFSType(0xEF53, nil)
+	assert.NoError(t, checkTmpfs("/fake", TmpfsCheckNone))
+	assert.NoError(t, checkTmpfs("/fake", TmpfsCheckWarn))
+	err := checkTmpfs("/fake", TmpfsCheckError)
+	s, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, s.Code(), codes.FailedPrecondition)
+
+	// Test statfs failure
+	fsType = fakeFSType(0, fmt.Errorf("statfs failed"))
+	assert.NoError(t, checkTmpfs("/fake", TmpfsCheckWarn))
+	err = checkTmpfs("/fake", TmpfsCheckError)
+	s, ok = status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, s.Code(), codes.Internal)
+}
+
+func TestValidTmpfsCheck(t *testing.T) {
+	assert.NoError(t, validTmpfsCheck(""))
+	assert.NoError(t, validTmpfsCheck(TmpfsCheckWarn))
+	assert.Error(t, validTmpfsCheck("fatal"))
+}